@@ -0,0 +1,250 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// Options represents configurable options used to create client and server TLS configurations.
+type Options struct {
+	KeyFile    string
+	CertFile   string
+	CaCertFile string
+
+	// CaCertDir, if set, is a directory containing zero or more trusted CA
+	// certificates (*.crt and *.pem files). Every certificate found is
+	// appended to the same pool used by CaCertFile, so operators can trust
+	// multiple issuers without having to concatenate them into one file.
+	CaCertDir string
+
+	// CaCertPEM, CertPEM and KeyPEM carry raw PEM-encoded material instead
+	// of file paths, so callers that already hold certificates in memory
+	// (e.g. issued moments ago by a workload identity system) don't need to
+	// write them to disk first. When both a PEM field and its file-based
+	// counterpart are set, the in-memory material wins.
+	CaCertPEM []byte
+	CertPEM   []byte
+	KeyPEM    []byte
+
+	// GetClientCertificate, when set, is wired into tls.Config's field of
+	// the same name: it is called on every handshake that requests a
+	// client certificate, which lets short-lived, rotating certificates
+	// (e.g. SPIFFE SVIDs) stay current without rebuilding the TLS config
+	// or restarting the process.
+	GetClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+
+	InsecureSkipVerify bool
+}
+
+// certPool builds the RootCAs pool described by opts, seeded from the host's
+// system cert pool (except on Windows, where Go's system pool support is
+// unreliable, so callers must trust only what they explicitly configure).
+// CaCertPEM takes precedence over CaCertFile when both are set, and
+// CaCertDir is always merged in on top of whichever of the two provided the
+// base pool.
+func certPool(opts Options) (*x509.CertPool, error) {
+	pool, err := systemCertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(opts.CaCertPEM) > 0:
+		if !pool.AppendCertsFromPEM(opts.CaCertPEM) {
+			return nil, errors.New("failed to parse any certificates from the supplied CA PEM material")
+		}
+	case opts.CaCertFile != "":
+		if err := appendCertsFromFile(pool, opts.CaCertFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.CaCertDir != "" {
+		if err := AppendCertsFromDir(pool, opts.CaCertDir); err != nil {
+			return nil, err
+		}
+	}
+
+	return pool, nil
+}
+
+// systemCertPool returns a copy of the host's trusted root pool to use as
+// the base for certPool. On Windows it returns an empty pool instead: Go's
+// system pool support there has historically been incomplete, so Windows
+// callers end up trusting only the CA material they explicitly configure.
+func systemCertPool() (*x509.CertPool, error) {
+	if runtime.GOOS == "windows" {
+		return x509.NewCertPool(), nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load system cert pool")
+	}
+	return pool, nil
+}
+
+// clientCertificate builds the client certificate described by opts, if
+// any: in-memory PEM material takes precedence over a file pair when both
+// are set.
+func clientCertificate(opts Options) (*tls.Certificate, error) {
+	if len(opts.CertPEM) > 0 || len(opts.KeyPEM) > 0 {
+		cert, err := tls.X509KeyPair(opts.CertPEM, opts.KeyPEM)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse x509 key pair from PEM material")
+		}
+		return &cert, nil
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := CertFromFilePair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return &cert, nil
+	}
+	return nil, nil
+}
+
+// CertPoolFromFile returns an x509.CertPool containing the certificates
+// in the named file. If the file does not contain any certificates, an
+// error is returned instead.
+func CertPoolFromFile(filename string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if err := appendCertsFromFile(pool, filename); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// appendCertsFromFile reads filename and appends the PEM blocks it contains
+// to pool.
+func appendCertsFromFile(pool *x509.CertPool, filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errors.Wrapf(err, "can't read CA file: %s", filename)
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return errors.Errorf("failed to parse any certificates from %s", filename)
+	}
+	return nil
+}
+
+// AppendCertsFromDir reads every *.crt and *.pem file in dir and appends the
+// PEM blocks it finds to pool. It is used to let operators trust multiple
+// issuers by dropping certificates into a directory instead of having to
+// concatenate them into a single CA bundle. The returned error identifies
+// which file failed to parse.
+func AppendCertsFromDir(pool *x509.CertPool, dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.crt"))
+	if err != nil {
+		return errors.Wrapf(err, "can't list *.crt files in %s", dir)
+	}
+	pemFiles, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return errors.Wrapf(err, "can't list *.pem files in %s", dir)
+	}
+	files = append(files, pemFiles...)
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "can't read CA file: %s", file)
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.Errorf("failed to parse any certificates from %s", file)
+		}
+	}
+	return nil
+}
+
+// CertPoolFromDir is a convenience wrapper around AppendCertsFromDir that
+// starts from an empty pool.
+func CertPoolFromDir(dir string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if err := AppendCertsFromDir(pool, dir); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// CertFromFilePair returns a tls.Certificate containing the
+// certificate's public/private key pair from a pair of given PEM-encoded files.
+func CertFromFilePair(certFile, keyFile string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "could not read x509 key pair")
+	}
+	return cert, err
+}
+
+// NewClientTLS returns a tls.Config appropriate for client auth. When caFile
+// is set, RootCAs holds the system pool plus the certificates in caFile (on
+// Windows, only those in caFile); when caFile is empty, RootCAs is left nil
+// so the system pool applies.
+func NewClientTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cfg := tls.Config{}
+
+	if certFile != "" && keyFile != "" {
+		cert, err := CertFromFilePair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		cp, err := systemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		if err := appendCertsFromFile(cp, caFile); err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = cp
+	}
+
+	return &cfg, nil
+}
+
+// ClientConfig returns a TLS configuration for use by a client.
+func ClientConfig(opts Options) (cfg *tls.Config, err error) {
+	cfg = &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+
+	cert, err := clientCertificate(opts)
+	if err != nil {
+		return nil, errors.Errorf("could not load x509 key pair: %v", err)
+	}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{*cert}
+	}
+
+	if cfg.RootCAs, err = certPool(opts); err != nil {
+		return nil, err
+	}
+
+	if opts.GetClientCertificate != nil {
+		cfg.GetClientCertificate = opts.GetClientCertificate
+	}
+
+	return cfg, nil
+}
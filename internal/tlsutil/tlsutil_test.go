@@ -17,8 +17,10 @@ limitations under the License.
 package tlsutil
 
 import (
+	"crypto/tls"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -62,6 +64,56 @@ func testfile(t *testing.T, file string) (path string) {
 	return path
 }
 
+func TestClientConfigCADir(t *testing.T) {
+	caCertDir := testfile(t, "cadir")
+
+	opts := Options{CaCertDir: caCertDir}
+	cfg, err := ClientConfig(opts)
+	if err != nil {
+		t.Fatalf("error building tls client config: %v", err)
+	}
+	if cfg.RootCAs == nil {
+		t.Fatalf("mismatch tls RootCAs, expecting non-nil")
+	}
+	if runtime.GOOS == "windows" {
+		if got := len(cfg.RootCAs.Subjects()); got != 2 {
+			t.Fatalf("expecting 2 subjects in the pool, got %d", got)
+		}
+	} else {
+		if got := len(cfg.RootCAs.Subjects()); got <= 2 {
+			t.Fatalf("expecting more than 2 subjects in the pool, got %d", got)
+		}
+	}
+
+	t.Run("combined with a single CA file", func(t *testing.T) {
+		opts := Options{CaCertFile: testfile(t, testCaCertFile), CaCertDir: caCertDir}
+		cfg, err := ClientConfig(opts)
+		if err != nil {
+			t.Fatalf("error building tls client config: %v", err)
+		}
+		if runtime.GOOS == "windows" {
+			if got := len(cfg.RootCAs.Subjects()); got != 3 {
+				t.Fatalf("expecting 3 subjects in the pool, got %d", got)
+			}
+		} else {
+			if got := len(cfg.RootCAs.Subjects()); got <= 3 {
+				t.Fatalf("expecting more than 3 subjects in the pool, got %d", got)
+			}
+		}
+	})
+
+	t.Run("error identifies the offending file", func(t *testing.T) {
+		badDir := testfile(t, "cadir-invalid")
+		_, err := ClientConfig(Options{CaCertDir: badDir})
+		if err == nil {
+			t.Fatal("expected an error for a directory containing an unparsable certificate")
+		}
+		if !strings.Contains(err.Error(), "bad.crt") {
+			t.Fatalf("expected error to identify the offending file, got: %v", err)
+		}
+	})
+}
+
 func TestNewClientTLS(t *testing.T) {
 	certFile := testfile(t, testCertFile)
 	keyFile := testfile(t, testKeyFile)
@@ -136,3 +188,74 @@ func TestNewClientTLS(t *testing.T) {
 		}
 	})
 }
+
+func TestClientConfigPEMMaterial(t *testing.T) {
+	caCertPEM, caCert, caKey := generateCA(t)
+	certPEM, keyPEM := generateLeaf(t, "client", caCert, caKey)
+
+	cfg, err := ClientConfig(Options{
+		CaCertPEM: caCertPEM,
+		CertPEM:   certPEM,
+		KeyPEM:    keyPEM,
+	})
+	if err != nil {
+		t.Fatalf("error building tls client config: %v", err)
+	}
+	if got := len(cfg.Certificates); got != 1 {
+		t.Fatalf("expecting 1 client certificate, got %d", got)
+	}
+	if cfg.RootCAs == nil {
+		t.Fatal("expecting a non-nil RootCAs pool built from CaCertPEM")
+	}
+
+	t.Run("in-memory material wins when a file-based counterpart is also set", func(t *testing.T) {
+		cfg, err := ClientConfig(Options{
+			CaCertPEM:  caCertPEM,
+			CertPEM:    certPEM,
+			KeyPEM:     keyPEM,
+			CaCertFile: testfile(t, "does-not-exist.crt"),
+			CertFile:   testfile(t, "does-not-exist.crt"),
+			KeyFile:    testfile(t, "does-not-exist.key"),
+		})
+		if err != nil {
+			t.Fatalf("expected the in-memory material to be used instead of the (nonexistent) files: %v", err)
+		}
+		if len(cfg.Certificates) != 1 {
+			t.Fatalf("expecting 1 client certificate, got %d", len(cfg.Certificates))
+		}
+	})
+}
+
+func TestClientConfigGetClientCertificate(t *testing.T) {
+	_, caCert, caKey := generateCA(t)
+	certPEM, keyPEM := generateLeaf(t, "rotating-identity", caCert, caKey)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	cfg, err := ClientConfig(Options{
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			calls++
+			return &cert, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("error building tls client config: %v", err)
+	}
+	if cfg.GetClientCertificate == nil {
+		t.Fatal("expected GetClientCertificate to be wired into the tls.Config")
+	}
+
+	got, err := cfg.GetClientCertificate(&tls.CertificateRequestInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Certificate) == 0 {
+		t.Fatal("expected a non-empty certificate from the callback")
+	}
+	if calls != 1 {
+		t.Fatalf("expected the callback to be invoked once, got %d", calls)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cli describes the operating environment for the Helm CLI.
+//
+// Helm's environment encapsulates all of the service dependencies Helm has
+// from the operating system. This includes, but is not limited to, the
+// following:
+//   - Path to the Helm configuration files.
+//   - Environment variables
+//   - Configuration files.
+//
+// The packages underneath this directory may read Helm's environment, but
+// they are never allowed to modify it.
+package cli
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func defaultHelmHome() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".config", "helm")
+}
+
+// EnvSettings describes all of the environment settings used by Helm.
+type EnvSettings struct {
+	// PluginsDirectory is the path to the plugins directory.
+	PluginsDirectory string
+	// RegistryConfig is the path to the registry config file.
+	RegistryConfig string
+	// RepositoryConfig is the path to the repositories file.
+	RepositoryConfig string
+	// RepositoryCache is the path to the repository cache directory.
+	RepositoryCache string
+	// GettersFile is the path to the custom getters configuration file.
+	GettersFile string
+	// Debug indicates whether Helm is running in Debug mode.
+	Debug bool
+}
+
+// New returns default environment settings, sourced from process
+// environment variables falling back to Helm's default XDG-compliant paths.
+func New() *EnvSettings {
+	home := defaultHelmHome()
+	env := &EnvSettings{
+		PluginsDirectory: envOr("HELM_PLUGINS", filepath.Join(home, "plugins")),
+		RegistryConfig:   envOr("HELM_REGISTRY_CONFIG", filepath.Join(home, "registry.json")),
+		RepositoryConfig: envOr("HELM_REPOSITORY_CONFIG", filepath.Join(home, "repositories.yaml")),
+		RepositoryCache:  envOr("HELM_REPOSITORY_CACHE", filepath.Join(home, "cache")),
+		GettersFile:      envOr("HELM_GETTERS_FILE", filepath.Join(home, "getters.yaml")),
+	}
+	env.Debug, _ = getBoolEnv("HELM_DEBUG")
+	return env
+}
+
+func envOr(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+func getBoolEnv(name string) (bool, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return false, nil
+	}
+	return v == "1" || v == "true", nil
+}
+
+// EnvVars returns the Helm environment variable set, as it would be
+// presented in `helm env`, so it can be propagated to child processes such
+// as plugin and getter binaries.
+func (s *EnvSettings) EnvVars() map[string]string {
+	return map[string]string{
+		"HELM_PLUGINS":           s.PluginsDirectory,
+		"HELM_PLUGIN_DIR":        s.PluginsDirectory,
+		"HELM_REGISTRY_CONFIG":   s.RegistryConfig,
+		"HELM_REPOSITORY_CONFIG": s.RepositoryConfig,
+		"HELM_REPOSITORY_CACHE":  s.RepositoryCache,
+		"HELM_GETTERS_FILE":      s.GettersFile,
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/version"
+)
+
+// cachedFilePath returns the path a fully-verified download for digestHex
+// is stored at within cacheDir.
+func cachedFilePath(cacheDir, digestHex string) string {
+	return filepath.Join(cacheDir, digestHex+".tgz")
+}
+
+// getCached serves href from g.opts.cacheDir when possible, falling back to
+// a (resumable) download on a cache miss. It is only used once both
+// WithCacheDir and WithExpectedDigest have been set; only sha256 digests are
+// supported.
+func (g *HTTPGetter) getCached(href string) (*bytes.Buffer, error) {
+	if g.opts.digestAlgo != "sha256" {
+		return nil, errors.Errorf("unsupported digest algorithm %q: only sha256 is supported for cached downloads", g.opts.digestAlgo)
+	}
+	digestHex := strings.ToLower(g.opts.digestHex)
+
+	final := cachedFilePath(g.opts.cacheDir, digestHex)
+	if data, err := ioutil.ReadFile(final); err == nil {
+		if sha256Hex(data) == digestHex {
+			return bytes.NewBuffer(data), nil
+		}
+		// The cached file no longer matches its own name; treat it like a
+		// miss and re-download rather than serving corrupt data.
+	}
+
+	if err := os.MkdirAll(g.opts.cacheDir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "could not create cache directory %s", g.opts.cacheDir)
+	}
+
+	data, err := g.downloadResumable(href, final, digestHex)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(data), nil
+}
+
+// downloadResumable downloads href into <final>.partial, resuming from a
+// previous attempt when one is found, verifies the result against
+// digestHex, and atomically renames it into place as final.
+func (g *HTTPGetter) downloadResumable(href, final, digestHex string) ([]byte, error) {
+	partial := final + ".partial"
+	hashStateFile := partial + ".sha256state"
+
+	h := sha256.New()
+	var resumeFrom int64
+	if fi, err := os.Stat(partial); err == nil {
+		if state, serr := ioutil.ReadFile(hashStateFile); serr == nil {
+			if restoreHashState(h, state) {
+				resumeFrom = fi.Size()
+			}
+		}
+	}
+
+	client, err := g.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, href, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", g.userAgentOrDefault())
+	if g.opts.username != "" && g.opts.password != "" {
+		req.SetBasicAuth(g.opts.username, g.opts.password)
+	}
+
+	var out *os.File
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+		if out, err = os.OpenFile(partial, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return nil, err
+		}
+	} else {
+		if out, err = os.Create(partial); err != nil {
+			return nil, err
+		}
+	}
+	defer out.Close()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resumeFrom > 0 && resp.StatusCode != http.StatusPartialContent {
+		// The server ignored our Range request (or doesn't support
+		// Accept-Ranges): start the download over from scratch.
+		resumeFrom = 0
+		h = sha256.New()
+		if err := out.Close(); err != nil {
+			return nil, err
+		}
+		if out, err = os.Create(partial); err != nil {
+			return nil, err
+		}
+	}
+
+	if resumeFrom == 0 && resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch %s : %s", href, resp.Status)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(out, h), resp.Body); err != nil {
+		persistHashState(hashStateFile, h)
+		return nil, errors.Wrapf(err, "download of %s was interrupted; it will resume on the next attempt", href)
+	}
+
+	if got := fmt.Sprintf("%x", h.Sum(nil)); got != digestHex {
+		out.Close()
+		os.Remove(partial)
+		os.Remove(hashStateFile)
+		return nil, errors.Errorf("failed to verify %s: expected sha256 digest %s, got %s", href, digestHex, got)
+	}
+
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, final); err != nil {
+		return nil, err
+	}
+	os.Remove(hashStateFile)
+
+	return ioutil.ReadFile(final)
+}
+
+func (g *HTTPGetter) userAgentOrDefault() string {
+	if g.opts.userAgent != "" {
+		return g.opts.userAgent
+	}
+	return "Helm/" + strings.TrimPrefix(version.GetVersion(), "v")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%x", sum[:])
+}
+
+// persistHashState best-efforts saving h's internal state next to a partial
+// download so a later resume can continue hashing where this attempt left
+// off, rather than re-hashing bytes already on disk.
+func persistHashState(path string, h hash.Hash) {
+	m, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return
+	}
+	state, err := m.MarshalBinary()
+	if err != nil {
+		return
+	}
+	_ = ioutil.WriteFile(path, state, 0600)
+}
+
+func restoreHashState(h hash.Hash, state []byte) bool {
+	u, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return false
+	}
+	return u.UnmarshalBinary(state) == nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetCacheHit(t *testing.T) {
+	cacheDir := t.TempDir()
+	content := []byte("cached chart contents")
+	sum := sha256.Sum256(content)
+	digestHex := fmt.Sprintf("%x", sum[:])
+
+	if err := ioutil.WriteFile(cachedFilePath(cacheDir, digestHex), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		t.Fatal("expected a cache hit to short-circuit the network call")
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(
+		WithCacheDir(cacheDir),
+		WithExpectedDigest("sha256", digestHex),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := g.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(content) {
+		t.Errorf("expected the cached contents, got %q", buf.String())
+	}
+	if calls != 0 {
+		t.Errorf("expected 0 network calls on a cache hit, got %d", calls)
+	}
+}
+
+func TestGetCacheMissDownloadsAndPopulatesCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	content := []byte("freshly downloaded chart")
+	sum := sha256.Sum256(content)
+	digestHex := fmt.Sprintf("%x", sum[:])
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(
+		WithCacheDir(cacheDir),
+		WithExpectedDigest("sha256", digestHex),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := g.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(content) {
+		t.Errorf("expected the downloaded contents, got %q", buf.String())
+	}
+
+	if _, err := os.Stat(cachedFilePath(cacheDir, digestHex)); err != nil {
+		t.Errorf("expected the download to populate the cache: %v", err)
+	}
+}
+
+func TestGetCacheDigestMismatch(t *testing.T) {
+	cacheDir := t.TempDir()
+	wantDigest := strings.Repeat("0", 64)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not what was expected"))
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(
+		WithCacheDir(cacheDir),
+		WithExpectedDigest("sha256", wantDigest),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Get(srv.URL); err == nil {
+		t.Fatal("expected a digest mismatch error")
+	}
+
+	if _, err := os.Stat(filepath.Join(cacheDir, wantDigest+".tgz.partial")); !os.IsNotExist(err) {
+		t.Error("expected the partial file to be cleaned up after a digest mismatch")
+	}
+}
+
+func TestDownloadResumable(t *testing.T) {
+	cacheDir := t.TempDir()
+	full := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	sum := sha256.Sum256(full)
+	digestHex := fmt.Sprintf("%x", sum[:])
+
+	final := cachedFilePath(cacheDir, digestHex)
+	partial := final + ".partial"
+	splitAt := 10
+	if err := ioutil.WriteFile(partial, full[:splitAt], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Persist the hash state a real interrupted download would have left
+	// behind, so resumption continues hashing from byte splitAt instead of
+	// starting over.
+	h := sha256.New()
+	h.Write(full[:splitAt])
+	persistHashState(partial+".sha256state", h)
+
+	var sawRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader := r.Header.Get("Range")
+		if rangeHeader == "" {
+			w.Write(full)
+			return
+		}
+		sawRange = rangeHeader
+		var start int
+		if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-", &start); err != nil {
+			t.Fatalf("could not parse Range header %q: %v", rangeHeader, err)
+		}
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(full[start:])
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hg := g.(*HTTPGetter)
+
+	data, err := hg.downloadResumable(srv.URL, final, digestHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(full) {
+		t.Errorf("expected the full resumed content, got %q", string(data))
+	}
+	if want := fmt.Sprintf("bytes=%d-", splitAt); sawRange != want {
+		t.Errorf("expected the server to receive a %q Range request to resume the download, got %q", want, sawRange)
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// CustomGetterConfig describes a single external scheme handler, modeled on
+// git-lfs's custom transfer adapters. It is loaded from a getters
+// configuration file (cli.EnvSettings.GettersFile: by default getters.yaml
+// in Helm's configuration directory, or $HELM_GETTERS_FILE) so that
+// users can teach Helm to speak protocols it does not natively support
+// (S3 signing variants, artifact registries, IPFS, etc.) without
+// recompiling.
+type CustomGetterConfig struct {
+	// Schemes are the URL schemes this getter handles, e.g. "s3" or "ipfs".
+	Schemes []string `json:"schemes"`
+	// Path is the external binary that is spawned to service a Get.
+	Path string `json:"path"`
+	// Args are extra arguments passed to Path on every invocation.
+	Args []string `json:"args,omitempty"`
+	// Concurrent indicates the adapter may be invoked for multiple
+	// downloads at the same time. When false, Helm serializes calls into
+	// the adapter the same way git-lfs does for "standalone" adapters.
+	Concurrent bool `json:"concurrent,omitempty"`
+}
+
+// LoadCustomGetters reads a getters configuration file and returns the
+// configured custom getters. A missing file is not an error: it simply
+// means no custom getters are configured.
+func LoadCustomGetters(path string) ([]CustomGetterConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "could not read getters file %s", path)
+	}
+
+	var cfgs []CustomGetterConfig
+	if err := yaml.Unmarshal(b, &cfgs); err != nil {
+		return nil, errors.Wrapf(err, "could not parse getters file %s", path)
+	}
+	return cfgs, nil
+}
+
+// customGetterInit is the first message sent to the adapter, mirroring
+// git-lfs's "init" event. It carries everything the adapter needs to know
+// in order to retrieve the URL without Helm re-implementing the protocol.
+type customGetterInit struct {
+	Event   string              `json:"event"`
+	URL     string              `json:"url"`
+	Options customGetterOptions `json:"options"`
+	Digest  string              `json:"digest,omitempty"`
+}
+
+type customGetterOptions struct {
+	Username              string `json:"username,omitempty"`
+	Password              string `json:"password,omitempty"`
+	CAFile                string `json:"caFile,omitempty"`
+	CertFile              string `json:"certFile,omitempty"`
+	KeyFile               string `json:"keyFile,omitempty"`
+	InsecureSkipVerifyTLS bool   `json:"insecureSkipVerifyTLS,omitempty"`
+	Timeout               int64  `json:"timeoutSeconds,omitempty"`
+	UserAgent             string `json:"userAgent,omitempty"`
+}
+
+// customGetterDownload is the "download" request sent right after the init
+// message; no acknowledgment of init is read from the adapter.
+type customGetterDownload struct {
+	Event string `json:"event"`
+	URL   string `json:"url"`
+}
+
+// customGetterResponse is what the adapter writes back for a download
+// request: the path of the file it fetched, or an error describing why it
+// could not be fetched.
+type customGetterResponse struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Error string `json:"error,omitempty"`
+}
+
+// CustomGetter invokes an external binary to service Get requests for the
+// schemes it is registered for, speaking a small line-delimited JSON
+// protocol over the process's stdin/stdout.
+type CustomGetter struct {
+	opts     options
+	cfg      CustomGetterConfig
+	settings *cli.EnvSettings
+
+	// serialize is shared by every CustomGetter NewCustomGetterConstructor
+	// produces for the same configured adapter, so that cfg.Concurrent ==
+	// false serializes calls into the adapter across Providers.ByScheme
+	// calls, not just within a single CustomGetter instance. It is nil
+	// when the adapter allows concurrent invocations.
+	serialize *sync.Mutex
+}
+
+// NewCustomGetterConstructor returns a Constructor that spawns cfg.Path for
+// every Get, so it can be registered in a Providers list alongside the
+// built-in getters. When cfg.Concurrent is false, every Getter the returned
+// Constructor produces shares a single mutex, so calls into the adapter are
+// serialized the same way git-lfs serializes calls into a "standalone"
+// custom transfer adapter.
+func NewCustomGetterConstructor(cfg CustomGetterConfig, settings *cli.EnvSettings) Constructor {
+	var serialize *sync.Mutex
+	if !cfg.Concurrent {
+		serialize = &sync.Mutex{}
+	}
+	return func(options ...Option) (Getter, error) {
+		g := &CustomGetter{cfg: cfg, settings: settings, serialize: serialize}
+		for _, opt := range options {
+			opt(&g.opts)
+		}
+		return g, nil
+	}
+}
+
+// Get performs a Get by spawning the configured external binary.
+func (g *CustomGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+
+	if g.serialize != nil {
+		g.serialize.Lock()
+		defer g.serialize.Unlock()
+	}
+
+	cmd := exec.Command(g.cfg.Path, g.cfg.Args...)
+	cmd.Env = os.Environ()
+	if g.settings != nil {
+		for k, v := range g.settings.EnvVars() {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open stdin to custom getter %s", g.cfg.Path)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open stdout from custom getter %s", g.cfg.Path)
+	}
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "could not start custom getter %s", g.cfg.Path)
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	enc := json.NewEncoder(stdin)
+	dec := json.NewDecoder(bufio.NewReader(stdout))
+
+	init := customGetterInit{
+		Event:  "init",
+		URL:    href,
+		Digest: g.opts.digestHex,
+		Options: customGetterOptions{
+			Username:              g.opts.username,
+			Password:              g.opts.password,
+			CAFile:                g.opts.caFile,
+			CertFile:              g.opts.certFile,
+			KeyFile:               g.opts.keyFile,
+			InsecureSkipVerifyTLS: g.opts.insecureSkipVerifyTLS,
+			Timeout:               int64(g.opts.timeout.Seconds()),
+			UserAgent:             g.opts.userAgent,
+		},
+	}
+	if err := enc.Encode(init); err != nil {
+		return nil, errors.Wrapf(err, "could not send init message to custom getter %s", g.cfg.Path)
+	}
+
+	if err := enc.Encode(customGetterDownload{Event: "download", URL: href}); err != nil {
+		return nil, errors.Wrapf(err, "could not send download message to custom getter %s", g.cfg.Path)
+	}
+
+	var resp customGetterResponse
+	if err := dec.Decode(&resp); err != nil {
+		return nil, errors.Wrapf(err, "could not read response from custom getter %s", g.cfg.Path)
+	}
+	if resp.Error != "" {
+		return nil, errors.Errorf("custom getter %s failed to fetch %s: %s", g.cfg.Path, href, resp.Error)
+	}
+
+	data, err := ioutil.ReadFile(resp.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "custom getter %s reported path %s but it could not be read", g.cfg.Path, resp.Path)
+	}
+	return bytes.NewBuffer(data), nil
+}
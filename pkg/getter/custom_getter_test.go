@@ -0,0 +1,273 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// writeFakeAdapter installs a small script, modeled on a git-lfs custom
+// transfer adapter, that records the raw "init" message it received to
+// initReportPath and the HELM_PLUGIN_DIR it was started with to
+// envReportPath, so tests can verify both the init payload (e.g. the
+// expected digest) and Helm's environment were propagated to it, then for
+// every "download" message writes back the path of a fixed payload file so
+// CustomGetter.Get can read it.
+func writeFakeAdapter(t *testing.T, dir, payloadPath, initReportPath, envReportPath string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake adapter script is a shell script")
+	}
+
+	script := filepath.Join(dir, "fake-adapter.sh")
+	contents := "#!/bin/sh\n" +
+		"read init\n" +
+		"printf '%s' \"$init\" > \"" + initReportPath + "\"\n" +
+		"printf '%s' \"$HELM_PLUGIN_DIR\" > \"" + envReportPath + "\"\n" +
+		"while read -r line; do\n" +
+		"  printf '{\"path\":\"%s\",\"size\":0}\\n' \"" + payloadPath + "\"\n" +
+		"done\n"
+	if err := ioutil.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatalf("could not write fake adapter: %v", err)
+	}
+	return script
+}
+
+func TestCustomGetter(t *testing.T) {
+	dir := t.TempDir()
+
+	payload := filepath.Join(dir, "payload.txt")
+	if err := ioutil.WriteFile(payload, []byte("custom getter payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	initReport := filepath.Join(dir, "init-report.txt")
+	envReport := filepath.Join(dir, "env-report.txt")
+	script := writeFakeAdapter(t, dir, payload, initReport, envReport)
+
+	settings := cli.New()
+	settings.PluginsDirectory = dir
+
+	cfg := CustomGetterConfig{
+		Schemes: []string{"fake"},
+		Path:    script,
+	}
+
+	g, err := NewCustomGetterConstructor(cfg, settings)(WithExpectedDigest("sha256", "deadbeef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := g.Get("fake://example.com/chart.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "custom getter payload" {
+		t.Errorf("expected payload from the adapter-reported path, got %q", got)
+	}
+
+	gotEnv, err := ioutil.ReadFile(envReport)
+	if err != nil {
+		t.Fatalf("expected the adapter to have run and reported its environment: %v", err)
+	}
+	if want := settings.EnvVars()["HELM_PLUGIN_DIR"]; string(gotEnv) != want {
+		t.Errorf("expected HELM_PLUGIN_DIR %q to be propagated to the adapter, got %q", want, string(gotEnv))
+	}
+
+	gotInit, err := ioutil.ReadFile(initReport)
+	if err != nil {
+		t.Fatalf("expected the adapter to have recorded the init message: %v", err)
+	}
+	var init customGetterInit
+	if err := json.Unmarshal(gotInit, &init); err != nil {
+		t.Fatalf("could not parse recorded init message: %v", err)
+	}
+	if init.Digest != "deadbeef" {
+		t.Errorf("expected the init message to carry the expected digest, got %q", init.Digest)
+	}
+}
+
+// writeSlowFakeAdapter installs a script that, for every download request it
+// receives, appends a "start <ns>"/"end <ns>" pair to logPath around a short
+// sleep, so a test can check whether two overlapping invocations of the
+// adapter ran one after another or at the same time.
+func writeSlowFakeAdapter(t *testing.T, dir, payloadPath, logPath string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake adapter script is a shell script")
+	}
+
+	script := filepath.Join(dir, "slow-fake-adapter.sh")
+	contents := "#!/bin/sh\n" +
+		"read init\n" +
+		"while read -r line; do\n" +
+		"  printf 'start %s\\n' \"$(date +%s%N)\" >> \"" + logPath + "\"\n" +
+		"  sleep 0.2\n" +
+		"  printf 'end %s\\n' \"$(date +%s%N)\" >> \"" + logPath + "\"\n" +
+		"  printf '{\"path\":\"%s\",\"size\":0}\\n' \"" + payloadPath + "\"\n" +
+		"done\n"
+	if err := ioutil.WriteFile(script, []byte(contents), 0755); err != nil {
+		t.Fatalf("could not write fake adapter: %v", err)
+	}
+	return script
+}
+
+// parseAdapterLog reads the start/end nanosecond markers writeSlowFakeAdapter
+// records and returns them paired up as [start, end] intervals.
+func parseAdapterLog(t *testing.T, logPath string) [][2]int64 {
+	t.Helper()
+	contents, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("could not read adapter log: %v", err)
+	}
+
+	var marks []int64
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		ns, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			t.Fatalf("could not parse timestamp in log line %q: %v", line, err)
+		}
+		marks = append(marks, ns)
+	}
+	if len(marks)%2 != 0 {
+		t.Fatalf("expected an even number of start/end markers, got %d", len(marks))
+	}
+
+	var intervals [][2]int64
+	for i := 0; i < len(marks); i += 2 {
+		intervals = append(intervals, [2]int64{marks[i], marks[i+1]})
+	}
+	return intervals
+}
+
+func TestCustomGetterSerializesWhenNotConcurrent(t *testing.T) {
+	dir := t.TempDir()
+
+	payload := filepath.Join(dir, "payload.txt")
+	if err := ioutil.WriteFile(payload, []byte("slow adapter payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "adapter.log")
+	script := writeSlowFakeAdapter(t, dir, payload, logPath)
+
+	cfg := CustomGetterConfig{
+		Schemes:    []string{"fake"},
+		Path:       script,
+		Concurrent: false,
+	}
+	constructor := NewCustomGetterConstructor(cfg, cli.New())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g, err := constructor()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := g.Get("fake://example.com/chart.tgz"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	intervals := parseAdapterLog(t, logPath)
+	if len(intervals) != 2 {
+		t.Fatalf("expected 2 recorded invocations, got %d", len(intervals))
+	}
+	first, second := intervals[0], intervals[1]
+	if first[0] > second[0] {
+		first, second = second, first
+	}
+	if first[1] > second[0] {
+		t.Errorf("expected the two invocations not to overlap, got %v and %v", first, second)
+	}
+}
+
+func TestLoadCustomGetters(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing file is not an error", func(t *testing.T) {
+		cfgs, err := LoadCustomGetters(filepath.Join(dir, "does-not-exist.yaml"))
+		if err != nil {
+			t.Fatalf("expected no error for a missing getters file, got %v", err)
+		}
+		if len(cfgs) != 0 {
+			t.Fatalf("expected no configured getters, got %d", len(cfgs))
+		}
+	})
+
+	t.Run("schemes, path, args and concurrent are parsed", func(t *testing.T) {
+		getters := filepath.Join(dir, "getters.yaml")
+		contents := `
+- schemes: ["s3"]
+  path: /usr/local/bin/helm-s3-getter
+  args: ["--region", "us-east-1"]
+  concurrent: true
+`
+		if err := ioutil.WriteFile(getters, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cfgs, err := LoadCustomGetters(getters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfgs) != 1 {
+			t.Fatalf("expected 1 configured getter, got %d", len(cfgs))
+		}
+		got := cfgs[0]
+		if got.Schemes[0] != "s3" || got.Path != "/usr/local/bin/helm-s3-getter" || !got.Concurrent {
+			t.Fatalf("unexpected config: %+v", got)
+		}
+		if len(got.Args) != 2 || got.Args[0] != "--region" {
+			t.Fatalf("unexpected args: %+v", got.Args)
+		}
+	})
+}
+
+func TestAllIncludesConfiguredCustomGetters(t *testing.T) {
+	dir := t.TempDir()
+	getters := filepath.Join(dir, "getters.yaml")
+	contents := "- schemes: [\"fake\"]\n  path: /bin/true\n"
+	if err := ioutil.WriteFile(getters, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := cli.New()
+	settings.GettersFile = getters
+
+	if _, err := All(settings).ByScheme("fake"); err != nil {
+		t.Fatalf("expected a custom getter registered for scheme %q: %v", "fake", err)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/cli"
+)
+
+// Getter is an interface to support GET to the specified URL.
+type Getter interface {
+	// Get file content by url string
+	Get(url string, options ...Option) (*bytes.Buffer, error)
+}
+
+// Constructor is the function for every getter which creates a specific instance
+// according to the configuration
+type Constructor func(options ...Option) (Getter, error)
+
+// Provider represents any conforming getter.Getter and the scheme(s) it supports.
+type Provider struct {
+	Schemes []string
+	New     Constructor
+}
+
+// Provides returns true if the given scheme is supported by this provider.
+func (p Provider) Provides(scheme string) bool {
+	for _, i := range p.Schemes {
+		if i == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// Providers is a collection of Provider objects.
+type Providers []Provider
+
+// ByScheme returns a Getter from the first Provider that handles the given scheme.
+//
+// If no provider handles this scheme, this will return an error.
+func (p Providers) ByScheme(scheme string) (Getter, error) {
+	for _, pp := range p {
+		if pp.Provides(scheme) {
+			return pp.New()
+		}
+	}
+	return nil, errors.Errorf("scheme %q not supported", scheme)
+}
+
+// All finds all of the registered getters as a list of Provider instances.
+// The built-in getters are http/https and file. Any schemes configured
+// through settings.GettersFile (see LoadCustomGetters) are appended after
+// the built-ins, so a user-configured getter can only add new schemes, not
+// shadow Helm's own.
+func All(settings *cli.EnvSettings) Providers {
+	result := Providers{
+		{
+			Schemes: []string{"http", "https"},
+			New:     NewHTTPGetter,
+		},
+		{
+			Schemes: []string{"file"},
+			New:     NewLocalGetter,
+		},
+	}
+
+	if settings == nil {
+		return result
+	}
+
+	cfgs, err := LoadCustomGetters(settings.GettersFile)
+	if err != nil {
+		// A malformed getters file should not prevent Helm from running
+		// with the built-in getters. The parse error is dropped and no
+		// custom getter is registered, so ByScheme reports their schemes
+		// as not supported.
+		return result
+	}
+	for _, cfg := range cfgs {
+		result = append(result, Provider{
+			Schemes: cfg.Schemes,
+			New:     NewCustomGetterConstructor(cfg, settings),
+		})
+	}
+	return result
+}
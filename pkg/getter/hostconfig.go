@@ -0,0 +1,133 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"os"
+	"strings"
+)
+
+// HostOptions overrides the TLS settings an HTTPGetter would otherwise use
+// for a single host, the same way git-lfs consults GIT_SSL_NO_VERIFY and
+// per-host `http.<url>.sslVerify` settings.
+//
+// HostOptions can also come from the environment:
+// HELM_HTTP_SSL_NO_VERIFY_HOSTS is a comma-separated list of hosts (or
+// "*.example.com" patterns) that skip TLS verification,
+// HELM_HTTP_CA_FILE_<host> names a CA file, and HELM_HTTP_CLIENT_CERT_<host>
+// holds "certFile,keyFile"; <host> is the hostname as mangleHostEnvName
+// rewrites it.
+type HostOptions struct {
+	CAFile                string
+	CertFile              string
+	KeyFile               string
+	InsecureSkipVerifyTLS bool
+}
+
+// These are the environment variables consulted for per-host overrides. The
+// two *EnvBase names are prefixes, completed by a mangled version of the
+// hostname (see mangleHostEnvName).
+const (
+	hostConfigNoVerifyEnv     = "HELM_HTTP_SSL_NO_VERIFY_HOSTS"
+	hostConfigCAFileEnvBase   = "HELM_HTTP_CA_FILE_"
+	hostConfigCertPairEnvBase = "HELM_HTTP_CLIENT_CERT_"
+)
+
+// matchHostConfig resolves the HostOptions that apply to host, preferring
+// the longest matching suffix in cfg (so "*.example.com" matches
+// "charts.example.com"), and falling back to the ambient environment
+// variables described on HostOptions.
+func matchHostConfig(host string, cfg map[string]HostOptions) (HostOptions, bool) {
+	if opts, ok := longestSuffixMatch(host, cfg); ok {
+		return opts, true
+	}
+	return hostConfigFromEnviron(host)
+}
+
+// longestSuffixMatch returns the HostOptions whose key is the longest
+// matching suffix of host. A key of the form "*.example.com" matches any
+// subdomain of example.com (but not example.com itself); any other key must
+// match host exactly.
+func longestSuffixMatch(host string, cfg map[string]HostOptions) (HostOptions, bool) {
+	var (
+		best      HostOptions
+		bestLen   = -1
+		bestFound bool
+	)
+	for key, opts := range cfg {
+		if matchesHost(host, key) && len(key) > bestLen {
+			best, bestLen, bestFound = opts, len(key), true
+		}
+	}
+	return best, bestFound
+}
+
+func matchesHost(host, pattern string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// hostConfigFromEnviron looks up the environment variables documented on
+// HostOptions for the given host. Entries in HELM_HTTP_SSL_NO_VERIFY_HOSTS
+// may be exact hosts or "*.example.com" patterns; the CA file and client
+// certificate variables match only the exact (mangled) hostname.
+func hostConfigFromEnviron(host string) (HostOptions, bool) {
+	var (
+		opts  HostOptions
+		found bool
+	)
+
+	for _, h := range strings.Split(os.Getenv(hostConfigNoVerifyEnv), ",") {
+		if matchesHost(host, strings.TrimSpace(h)) {
+			opts.InsecureSkipVerifyTLS = true
+			found = true
+		}
+	}
+
+	name := mangleHostEnvName(host)
+	if ca := os.Getenv(hostConfigCAFileEnvBase + name); ca != "" {
+		opts.CAFile = ca
+		found = true
+	}
+	if pair := os.Getenv(hostConfigCertPairEnvBase + name); pair != "" {
+		if parts := strings.SplitN(pair, ",", 2); len(parts) == 2 {
+			opts.CertFile, opts.KeyFile = parts[0], parts[1]
+			found = true
+		}
+	}
+
+	return opts, found
+}
+
+// mangleHostEnvName turns a hostname into the form used by the
+// HELM_HTTP_CA_FILE_<host> / HELM_HTTP_CLIENT_CERT_<host> environment
+// variables: uppercased, with every character that isn't a letter, digit
+// or underscore replaced by an underscore.
+func mangleHostEnvName(host string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - ('a' - 'A')
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, host)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLongestSuffixMatch(t *testing.T) {
+	cfg := map[string]HostOptions{
+		"*.example.com":        {CAFile: "wildcard.crt"},
+		"charts.example.com":   {CAFile: "exact.crt"},
+		"internal.example.com": {InsecureSkipVerifyTLS: true},
+	}
+
+	tests := []struct {
+		host     string
+		wantFile string
+		wantOK   bool
+	}{
+		{"charts.example.com", "exact.crt", true},
+		{"other.example.com", "wildcard.crt", true},
+		{"example.com", "", false},
+		{"unrelated.org", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := longestSuffixMatch(tt.host, cfg)
+		if ok != tt.wantOK {
+			t.Errorf("longestSuffixMatch(%q): expected ok=%t, got %t", tt.host, tt.wantOK, ok)
+			continue
+		}
+		if ok && got.CAFile != tt.wantFile {
+			t.Errorf("longestSuffixMatch(%q): expected CAFile %q, got %q", tt.host, tt.wantFile, got.CAFile)
+		}
+	}
+}
+
+func TestResolveTLSOptionsPrecedence(t *testing.T) {
+	g := &HTTPGetter{}
+	g.opts.hostConfig = map[string]HostOptions{
+		"*.example.com": {CAFile: "host-ca.crt"},
+	}
+
+	t.Run("per-host config applies when no explicit options are set", func(t *testing.T) {
+		resolved := g.resolveTLSOptions("charts.example.com")
+		if resolved.CAFile != "host-ca.crt" {
+			t.Errorf("expected the per-host CA file to apply, got %q", resolved.CAFile)
+		}
+	})
+
+	t.Run("explicit Get options win over per-host config", func(t *testing.T) {
+		g2 := &HTTPGetter{}
+		g2.opts.hostConfig = g.opts.hostConfig
+		g2.opts.caFile = "explicit-ca.crt"
+
+		resolved := g2.resolveTLSOptions("charts.example.com")
+		if resolved.CAFile != "explicit-ca.crt" {
+			t.Errorf("expected the explicit CA file to win, got %q", resolved.CAFile)
+		}
+	})
+
+	t.Run("no match falls back to global defaults", func(t *testing.T) {
+		resolved := g.resolveTLSOptions("unrelated.org")
+		if resolved.CAFile != "" || resolved.InsecureSkipVerifyTLS {
+			t.Errorf("expected global defaults for an unmatched host, got %+v", resolved)
+		}
+	})
+}
+
+// TestHTTPGetterHostConfigAcrossRedirectHosts drives a real redirect from
+// one host to another and verifies the per-host TLS profile is re-resolved
+// for each host involved, rather than resolved once from the initial URL's
+// host and reused for the rest of the request. "localhost" and "127.0.0.1"
+// both resolve to the loopback interface but are distinct hostnames, which
+// lets a single-machine test exercise two different per-host profiles: the
+// redirect target serves a self-signed certificate, so the redirect only
+// succeeds if its own host's InsecureSkipVerifyTLS entry is consulted when
+// that hop is dialed.
+func TestHTTPGetterHostConfigAcrossRedirectHosts(t *testing.T) {
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("target response"))
+	}))
+	defer target.Close()
+
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer redirector.Close()
+
+	initialURL := strings.Replace(redirector.URL, "127.0.0.1", "localhost", 1)
+
+	g, err := NewHTTPGetter(WithHostConfig(map[string]HostOptions{
+		// "localhost" (the initial host, served over plain HTTP) gets no
+		// special treatment.
+		"localhost": {},
+		// "127.0.0.1" (the redirect target's host) opts out of verification
+		// for its self-signed certificate.
+		"127.0.0.1": {InsecureSkipVerifyTLS: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := g.Get(initialURL, WithURL(initialURL))
+	if err != nil {
+		t.Fatalf("expected the redirect to the self-signed target to succeed under its own host's profile: %v", err)
+	}
+	if got := buf.String(); got != "target response" {
+		t.Errorf("expected the response body from the redirect target, got %q", got)
+	}
+}
+
+func TestHostConfigFromEnviron(t *testing.T) {
+	t.Setenv(hostConfigNoVerifyEnv, "insecure.example.com,*.staging.example.com")
+	t.Setenv(hostConfigCAFileEnvBase+"CHARTS_EXAMPLE_COM", "/etc/helm/ca.crt")
+
+	g := &HTTPGetter{}
+
+	t.Run("HELM_HTTP_SSL_NO_VERIFY_HOSTS disables verification for listed hosts", func(t *testing.T) {
+		if !g.resolveTLSOptions("insecure.example.com").InsecureSkipVerifyTLS {
+			t.Fatal("expected insecure.example.com to skip TLS verification")
+		}
+		if !g.resolveTLSOptions("preview.staging.example.com").InsecureSkipVerifyTLS {
+			t.Fatal("expected a *.staging.example.com entry to match a subdomain")
+		}
+		if g.resolveTLSOptions("other.example.com").InsecureSkipVerifyTLS {
+			t.Fatal("did not expect an unrelated host to skip TLS verification")
+		}
+	})
+
+	t.Run("HELM_HTTP_CA_FILE_<host> is matched by mangled hostname", func(t *testing.T) {
+		if got := g.resolveTLSOptions("charts.example.com").CAFile; got != "/etc/helm/ca.crt" {
+			t.Fatalf("expected the env-configured CA file, got %q", got)
+		}
+	})
+}
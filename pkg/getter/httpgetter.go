@@ -0,0 +1,207 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/tlsutil"
+	"helm.sh/helm/v3/internal/version"
+)
+
+// maxRedirects bounds the redirects HTTPGetter follows, matching the limit
+// Go's own http.Client enforces by default.
+const maxRedirects = 10
+
+// HTTPGetter is the default HTTP(/S) backend handler
+type HTTPGetter struct {
+	opts options
+}
+
+// Get performs a Get from repo.Getter and returns the body.
+func (g *HTTPGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.get(href)
+}
+
+func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
+	if g.opts.cacheDir != "" && g.opts.digestHex != "" {
+		return g.getCached(href)
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	client, err := g.httpClient()
+	if err != nil {
+		return buf, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, href, nil)
+	if err != nil {
+		return buf, err
+	}
+
+	req.Header.Set("User-Agent", "Helm/"+strings.TrimPrefix(version.GetVersion(), "v"))
+
+	if g.opts.userAgent != "" {
+		req.Header.Set("User-Agent", g.opts.userAgent)
+	}
+
+	if g.opts.username != "" && g.opts.password != "" {
+		req.SetBasicAuth(g.opts.username, g.opts.password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return buf, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return buf, errors.Errorf("failed to fetch %s : %s", href, resp.Status)
+	}
+
+	_, err = io.Copy(buf, resp.Body)
+	return buf, err
+}
+
+// NewHTTPGetter constructs a valid http/https client as a Getter
+func NewHTTPGetter(options ...Option) (Getter, error) {
+	var client HTTPGetter
+
+	for _, opt := range options {
+		opt(&client.opts)
+	}
+
+	return &client, nil
+}
+
+// resolveTLSOptions determines the effective TLS settings for host. Explicit
+// Get options (WithTLSClientConfig, WithInsecureSkipVerifyTLS) always win;
+// otherwise the per-host config given to WithHostConfig (longest suffix
+// match) or the HELM_HTTP_* environment variables apply; failing both, the
+// getter's global defaults (normally empty/false) are used.
+func (g *HTTPGetter) resolveTLSOptions(host string) HostOptions {
+	resolved := HostOptions{
+		CAFile:                g.opts.caFile,
+		CertFile:              g.opts.certFile,
+		KeyFile:               g.opts.keyFile,
+		InsecureSkipVerifyTLS: g.opts.insecureSkipVerifyTLS,
+	}
+	if resolved.CAFile == "" && resolved.CertFile == "" && resolved.KeyFile == "" && !resolved.InsecureSkipVerifyTLS {
+		if hostOpts, ok := matchHostConfig(host, g.opts.hostConfig); ok {
+			resolved = hostOpts
+		}
+	}
+	return resolved
+}
+
+// httpClient builds a fresh *http.Client for this Get. The TLS config is not
+// resolved up front from the request's initial host: DialTLSContext resolves
+// it once per connection, from the host that connection is actually being
+// made to. That is what lets a redirect to a different host pick up that
+// host's own HostOptions (an InsecureSkipVerifyTLS entry for the redirect
+// target, say) instead of reusing whatever was resolved for the original
+// URL. It also means rotated on-disk CA/cert/key material is re-read on
+// every connection rather than baked into a long-lived cached client.
+func (g *HTTPGetter) httpClient() (*http.Client, error) {
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DisableCompression: true,
+		Proxy:              http.ProxyFromEnvironment,
+		DialTLSContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			resolved := g.resolveTLSOptions(host)
+			tlsConf, err := g.tlsConfig(host, resolved)
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+
+			tlsConn := tls.Client(conn, tlsConf)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		},
+	}
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       g.opts.timeout,
+		CheckRedirect: g.checkRedirect,
+	}, nil
+}
+
+// checkRedirect replicates the 10-redirect cap Go's http.Client enforces by
+// default (setting CheckRedirect at all disables that default), and, when
+// WithPassCredentialsAll was given, re-applies HTTP Basic Auth to the
+// redirected request. Go's Client strips the Authorization header whenever
+// a redirect changes host, port or scheme, so without this the credentials
+// supplied for the original host would silently vanish on any cross-host
+// redirect.
+func (g *HTTPGetter) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if g.opts.passCredentialsAll && g.opts.username != "" && g.opts.password != "" {
+		req.SetBasicAuth(g.opts.username, g.opts.password)
+	}
+	return nil
+}
+
+// tlsConfig builds the tls.Config for the given host and resolved per-host
+// options, preferring in-memory PEM material over file paths when both are
+// supplied.
+func (g *HTTPGetter) tlsConfig(host string, resolved HostOptions) (*tls.Config, error) {
+	tlsConf, err := tlsutil.ClientConfig(tlsutil.Options{
+		CertFile:             resolved.CertFile,
+		KeyFile:              resolved.KeyFile,
+		CaCertFile:           resolved.CAFile,
+		CaCertDir:            g.opts.caDir,
+		CaCertPEM:            g.opts.caCertPEM,
+		CertPEM:              g.opts.certPEM,
+		KeyPEM:               g.opts.keyPEM,
+		GetClientCertificate: g.opts.getClientCertificate,
+		InsecureSkipVerify:   resolved.InsecureSkipVerifyTLS,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create new TLS config")
+	}
+	tlsConf.ServerName = host
+	return tlsConf, nil
+}
@@ -24,6 +24,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -228,6 +229,41 @@ func TestDownloadTLS(t *testing.T) {
 	}
 }
 
+func TestHTTPGetterCADir(t *testing.T) {
+	cd := "../../testdata"
+	ca, pub, priv, caDir := filepath.Join(cd, "rootca.crt"), filepath.Join(cd, "crt.pem"), filepath.Join(cd, "key.pem"), filepath.Join(cd, "cadir")
+
+	g, err := NewHTTPGetter(
+		WithTLSClientConfig(pub, priv, ca),
+		WithCADir(caDir),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hg, ok := g.(*HTTPGetter)
+	if !ok {
+		t.Fatal("expected NewHTTPGetter to produce an *HTTPGetter")
+	}
+
+	// httpClient builds its TLS config per connection, so check the config
+	// its dialer builds for a connection to localhost.
+	tlsConf, err := hg.tlsConfig("localhost", hg.resolveTLSOptions("localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tlsConf.RootCAs == nil {
+		t.Fatal("expected a non-nil RootCAs pool combining the CA file and CA directory")
+	}
+
+	if runtime.GOOS != "windows" {
+		if got := len(tlsConf.RootCAs.Subjects()); got <= 1 {
+			t.Fatalf("expected subjects from both the CA file and the CA directory, got %d", got)
+		}
+	}
+}
+
 func TestDownloadTLSWithRedirect(t *testing.T) {
 	cd := "../../testdata"
 	srv2Resp := "hello"
@@ -0,0 +1,60 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// LocalGetter is the efault getter for file:// URLs
+type LocalGetter struct {
+	opts options
+}
+
+// Get performs a Get from repo.Getter and returns the body.
+func (g *LocalGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.get(href)
+}
+
+// NewLocalGetter constructs a valid local getter
+func NewLocalGetter(options ...Option) (Getter, error) {
+	var result LocalGetter
+	for _, opt := range options {
+		opt(&result.opts)
+	}
+	return &result, nil
+}
+
+func (g *LocalGetter) get(href string) (*bytes.Buffer, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid file path %s", href)
+	}
+
+	data, err := ioutil.ReadFile(u.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read file %s", href)
+	}
+	return bytes.NewBuffer(data), nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// generateMTLSCA and generateMTLSIdentity provide just enough of an x509 CA
+// to exercise rotating client identities end-to-end against a real TLS
+// server; they intentionally duplicate internal/tlsutil's test helpers
+// rather than import an internal package's unexported test code.
+func generateMTLSCA(t *testing.T) (*x509.Certificate, []byte, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), key
+}
+
+func generateMTLSIdentity(t *testing.T, serial int64, commonName string, ca *x509.Certificate, caKey *rsa.PrivateKey) tls.Certificate {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
+
+// TestHTTPGetterRotatingClientCertificate spins up a TLS server that
+// requires a client certificate and echoes back the presented identity's
+// CommonName, then verifies that rotating the certificate returned by a
+// WithClientCertificateCallback takes effect on the very next Get, with no
+// need to rebuild the getter.
+func TestHTTPGetterRotatingClientCertificate(t *testing.T) {
+	ca, caPEM, caKey := generateMTLSCA(t)
+	identityA := generateMTLSIdentity(t, 2, "workload-a", ca, caKey)
+	identityB := generateMTLSIdentity(t, 3, "workload-b", ca, caKey)
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caPEM) {
+		t.Fatal("could not load generated CA into pool")
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cn := r.TLS.PeerCertificates[0].Subject.CommonName
+		fmt.Fprint(w, cn)
+	}))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  caPool,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	var (
+		mu      sync.Mutex
+		current = identityA
+	)
+	g, err := NewHTTPGetter(
+		WithURL(srv.URL),
+		WithInsecureSkipVerifyTLS(true),
+		WithClientCertificateCallback(func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			cert := current
+			return &cert, nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := g.Get(srv.URL, WithURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "workload-a" {
+		t.Fatalf("expected the server to see workload-a, got %q", got)
+	}
+
+	mu.Lock()
+	current = identityB
+	mu.Unlock()
+
+	buf, err = g.Get(srv.URL, WithURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "workload-b" {
+		t.Fatalf("expected the rotated certificate workload-b to take effect without rebuilding the getter, got %q", got)
+	}
+}
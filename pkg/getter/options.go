@@ -0,0 +1,181 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+type options struct {
+	url      string
+	certFile string
+	keyFile  string
+	caFile   string
+	// caDir, when set, names a directory of additional CA certificates
+	// (*.crt/*.pem) that are merged into the pool built from caFile.
+	caDir                 string
+	insecureSkipVerifyTLS bool
+	username              string
+	password              string
+	passCredentialsAll    bool
+	userAgent             string
+	timeout               time.Duration
+	// hostConfig supplies per-host TLS settings, used only when certFile,
+	// keyFile, caFile and insecureSkipVerifyTLS are all unset; see
+	// WithHostConfig and matchHostConfig.
+	hostConfig map[string]HostOptions
+
+	// cacheDir, digestAlgo and digestHex back a content-addressable cache
+	// of downloaded files; see WithCacheDir and WithExpectedDigest.
+	cacheDir   string
+	digestAlgo string
+	digestHex  string
+
+	// caCertPEM, certPEM and keyPEM carry in-memory TLS material; see
+	// WithTLSClientMaterial. getClientCertificate lets that material be
+	// rotated without restarting the process; see
+	// WithClientCertificateCallback.
+	caCertPEM            []byte
+	certPEM              []byte
+	keyPEM               []byte
+	getClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+}
+
+// Option allows specifying various settings configurable by the user for overriding the defaults
+// used when performing Get operations with the Getter.
+type Option func(*options)
+
+// WithURL records the URL the getter will fetch objects from. HTTPGetter does
+// not take the TLS server name from it: each TLS connection uses the host it
+// is dialed to as its server name.
+func WithURL(url string) Option {
+	return func(opts *options) {
+		opts.url = url
+	}
+}
+
+// WithBasicAuth sets the request's Authorization header to use the provided credentials
+func WithBasicAuth(username, password string) Option {
+	return func(opts *options) {
+		opts.username = username
+		opts.password = password
+	}
+}
+
+func WithPassCredentialsAll(pass bool) Option {
+	return func(opts *options) {
+		opts.passCredentialsAll = pass
+	}
+}
+
+// WithUserAgent sets the request's User-Agent header to use the provided agent name.
+func WithUserAgent(userAgent string) Option {
+	return func(opts *options) {
+		opts.userAgent = userAgent
+	}
+}
+
+// WithInsecureSkipVerifyTLS determines if a TLS Certificate will be checked
+func WithInsecureSkipVerifyTLS(insecureSkipVerifyTLS bool) Option {
+	return func(opts *options) {
+		opts.insecureSkipVerifyTLS = insecureSkipVerifyTLS
+	}
+}
+
+// WithTLSClientConfig sets the client auth with the provided credentials.
+func WithTLSClientConfig(certFile, keyFile, caFile string) Option {
+	return func(opts *options) {
+		opts.certFile = certFile
+		opts.keyFile = keyFile
+		opts.caFile = caFile
+	}
+}
+
+// WithCADir adds a directory of trusted CA certificates (*.crt/*.pem files)
+// whose subjects are merged into RootCAs alongside the single CA file set
+// via WithTLSClientConfig, allowing operators to trust multiple issuers by
+// dropping certificates into a directory instead of concatenating them.
+func WithCADir(path string) Option {
+	return func(opts *options) {
+		opts.caDir = path
+	}
+}
+
+// WithTimeout sets the timeout for requests
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+// WithHostConfig overrides the TLS options an HTTPGetter otherwise uses for
+// requests to the given hosts. A key of the form "*.example.com" matches any
+// subdomain of example.com. Options set with WithTLSClientConfig or
+// WithInsecureSkipVerifyTLS take precedence: only when none of them is set
+// is each connection's host resolved against this map (longest matching key
+// wins), then against the environment variables documented on HostOptions.
+// A directory given to WithCADir is merged into the CA pool in every case.
+func WithHostConfig(cfg map[string]HostOptions) Option {
+	return func(opts *options) {
+		opts.hostConfig = cfg
+	}
+}
+
+// WithCacheDir backs Get with an on-disk, content-addressable cache: a hit
+// short-circuits the network call entirely, and a miss downloads into the
+// cache directory and can be resumed if it is interrupted. WithCacheDir has
+// no effect unless WithExpectedDigest is also given, since the cache is
+// keyed by the expected digest.
+func WithCacheDir(path string) Option {
+	return func(opts *options) {
+		opts.cacheDir = path
+	}
+}
+
+// WithExpectedDigest tells Get the digest the downloaded file is expected to
+// have. Only "sha256" is currently supported. Combined with WithCacheDir,
+// this lets repeated downloads of the same chart version become a cache hit
+// instead of a network round trip.
+func WithExpectedDigest(algo, hex string) Option {
+	return func(opts *options) {
+		opts.digestAlgo = algo
+		opts.digestHex = hex
+	}
+}
+
+// WithTLSClientMaterial sets the client auth from in-memory PEM data,
+// paralleling WithTLSClientConfig for callers that already hold their
+// certificates in memory instead of on disk. If both are given for the same
+// Get, the in-memory material wins.
+func WithTLSClientMaterial(caPEM, certPEM, keyPEM []byte) Option {
+	return func(opts *options) {
+		opts.caCertPEM = caPEM
+		opts.certPEM = certPEM
+		opts.keyPEM = keyPEM
+	}
+}
+
+// WithClientCertificateCallback wires cb into the TLS config's
+// GetClientCertificate, so a workload identity system can hand out
+// short-lived certificates that rotate without Helm having to rebuild its
+// TLS config or restart.
+func WithClientCertificateCallback(cb func(*tls.CertificateRequestInfo) (*tls.Certificate, error)) Option {
+	return func(opts *options) {
+		opts.getClientCertificate = cb
+	}
+}